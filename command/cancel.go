@@ -0,0 +1,63 @@
+package command
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultShutdownGrace is how long in-flight deletes get to finish after the
+// first SIGINT/SIGTERM before outstanding requests are killed; a second
+// signal within that window kills them at once.
+const defaultShutdownGrace = 10 * time.Second
+
+// newShutdownContext returns two contexts derived from context.Background():
+// ctx, which is canceled on the first SIGINT/SIGTERM so callers can stop
+// starting new work and let in-flight requests finish; and killCtx, which is
+// canceled on a second signal received within grace of the first, or once
+// grace has elapsed without one, so callers can abort outstanding requests
+// via their WithContext variants. cancel must
+// be called once the command has finished to stop the signal goroutine.
+func newShutdownContext(grace time.Duration) (ctx context.Context, killCtx context.Context, cancel func()) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	killCtx, cancelKillCtx := context.WithCancel(context.Background())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-sigCh:
+		case <-done:
+			return
+		}
+
+		log.Warn("received interrupt, waiting for in-flight deletes to finish (press Ctrl-C again to force)")
+		cancelCtx()
+
+		select {
+		case <-sigCh:
+			log.Warn("received second interrupt, killing outstanding requests")
+			cancelKillCtx()
+		case <-time.After(grace):
+			log.Warnf("shutdown grace period (%s) elapsed, killing outstanding requests", grace)
+			cancelKillCtx()
+		case <-done:
+		}
+	}()
+
+	cleanup := func() {
+		close(done)
+		signal.Stop(sigCh)
+		cancelCtx()
+		cancelKillCtx()
+	}
+
+	return ctx, killCtx, cleanup
+}
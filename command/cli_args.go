@@ -0,0 +1,44 @@
+package command
+
+import (
+	"os"
+
+	"github.com/mattn/go-shellwords"
+)
+
+// cliArgsEnv is the TF_CLI_ARGS-style environment variable whose value is
+// tokenized and prepended to the command line, so CI pipelines can force
+// flags like --dry-run or --max-retries=50 for a whole job without wrapping
+// the binary invocation.
+const cliArgsEnv = "AWSWEEPER_CLI_ARGS"
+
+// cliArgsSubcommandEnv is the subcommand-scoped variant, the way Terraform
+// supports TF_CLI_ARGS_plan; awsweeper only has "wipe" today, so it is read
+// on every run alongside cliArgsEnv.
+const cliArgsSubcommandEnv = cliArgsEnv + "_wipe"
+
+// prependEnvArgs tokenizes AWSWEEPER_CLI_ARGS and AWSWEEPER_CLI_ARGS_wipe (in
+// that order, general before subcommand-scoped) and prepends the resulting
+// tokens to args, mirroring how Terraform's main.go honors TF_CLI_ARGS.
+func prependEnvArgs(args []string) ([]string, error) {
+	var prepend []string
+
+	for _, env := range []string{cliArgsEnv, cliArgsSubcommandEnv} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+
+		tokens, err := shellwords.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		prepend = append(prepend, tokens...)
+	}
+
+	if len(prepend) == 0 {
+		return args, nil
+	}
+
+	return append(prepend, args...), nil
+}
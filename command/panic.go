@@ -0,0 +1,125 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mitchellh/panicwrap"
+	log "github.com/sirupsen/logrus"
+)
+
+// panicOutputLines is the number of trailing lines of captured stderr that
+// are included in the crash log alongside the goroutine dump, so a crash
+// report is useful without also being unbounded in size.
+const panicOutputLines = 500
+
+// wrapPanic re-execs the current binary as a panicwrap-monitored child,
+// following the pattern used by Terraform's main.go. It returns the exit
+// status and true when this invocation was the parent (and the caller
+// should simply return that status), or 0 and false when this invocation
+// is the child (or forking is disabled) and should continue into realMain.
+func wrapPanic() (int, bool) {
+	if os.Getenv("AWSWEEPER_FORK") == "0" {
+		return 0, false
+	}
+
+	if panicwrap.Wrapped(&panicwrap.WrapConfig{}) {
+		return 0, false
+	}
+
+	tempFile, err := ioutil.TempFile("", "awsweeper-panic")
+	if err != nil {
+		log.WithError(err).Warn("failed to create temp file for panic handling, continuing without it")
+		return 0, false
+	}
+
+	logWriter, err := crashLogWriter()
+	if err != nil {
+		log.WithError(err).Warn("failed to open crash log, continuing without it")
+		logWriter = ioutil.Discard
+	}
+
+	panicked := false
+	exitStatus, err := panicwrap.Wrap(&panicwrap.WrapConfig{
+		Handler: panicHandler(tempFile.Name(), &panicked),
+		Writer:  io.MultiWriter(tempFile, logWriter, os.Stderr),
+	})
+	if err != nil {
+		log.WithError(err).Error("failed to set up panic wrapper")
+		os.Remove(tempFile.Name())
+		return 0, false
+	}
+
+	// exitStatus is -1 when this process is the child; fall through to realMain.
+	if exitStatus < 0 {
+		return 0, false
+	}
+
+	// Only clean up the temp file on a clean exit; on a panic exit it backs
+	// the path the handler just told the user their crash report lives at.
+	if !panicked {
+		os.Remove(tempFile.Name())
+	}
+
+	return exitStatus, true
+}
+
+// panicHandler formats the goroutine dump captured by panicwrap plus the
+// last panicOutputLines of the child's stderr (read back from the temp file)
+// into an actionable report and prints it to stderr. The child's stderr,
+// panic included, reaches ~/.awsweeper/crash.log and the temp file through
+// the Writer set up in wrapPanic.
+func panicHandler(tempFilePath string, panicked *bool) panicwrap.HandlerFunc {
+	return func(dump string) {
+		*panicked = true
+
+		var tail string
+		if captured, err := ioutil.ReadFile(tempFilePath); err == nil {
+			tail = lastLines(string(captured), panicOutputLines)
+		}
+
+		report := fmt.Sprintf(
+			"awsweeper crashed at %s.\n\n"+
+				"A full crash report has been saved at %q and to ~/.awsweeper/crash.log.\n"+
+				"Please open an issue with the contents of that file.\n\n%s\n%s",
+			time.Now().UTC().Format(time.RFC3339), tempFilePath, dump, tail,
+		)
+
+		fmt.Fprintln(os.Stderr, report)
+	}
+}
+
+// crashLogWriter opens ~/.awsweeper/crash.log for appending, creating the
+// directory if necessary.
+func crashLogWriter() (io.Writer, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(home, ".awsweeper")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return os.OpenFile(filepath.Join(dir, "crash.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// lastLines returns at most n trailing lines of s.
+func lastLines(s string, n int) string {
+	found := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		// a trailing newline ends the last line rather than starting a new one
+		if s[i] == '\n' && i != len(s)-1 {
+			found++
+			if found == n {
+				return s[i+1:]
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,118 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-plugin"
+	tfplugin "github.com/hashicorp/terraform/plugin"
+	"github.com/hashicorp/terraform/terraform"
+	log "github.com/sirupsen/logrus"
+)
+
+// reattachConfig is one entry of the TF_REATTACH_PROVIDERS JSON map, in the
+// exact shape Terraform itself emits (and reads) for `terraform plan
+// -reattach`. Only the fields awsweeper needs to dial the plugin are kept.
+type reattachConfig struct {
+	Protocol        string `json:"Protocol"`
+	ProtocolVersion int    `json:"ProtocolVersion"`
+	Pid             int    `json:"Pid"`
+	Addr            struct {
+		Network string `json:"Network"`
+		String  string `json:"String"`
+	} `json:"Addr"`
+}
+
+// reattachProvider dials an already-running terraform-provider-aws process
+// described by raw (the same JSON that TF_REATTACH_PROVIDERS carries, either
+// from --provider-reattach or the environment) instead of instantiating the
+// provider in-process. It returns the provider and a stop function that the
+// caller must call on shutdown to release the plugin client without leaking
+// the provider's global stop-context goroutine.
+func reattachProvider(raw string) (terraform.ResourceProvider, func(), error) {
+	var configs map[string]reattachConfig
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse reattach config: %s", err)
+	}
+
+	var cfg reattachConfig
+	found := false
+	for addr, c := range configs {
+		log.Debugf("found reattach config for provider %q", addr)
+
+		// Provider addresses look like "registry.terraform.io/-/aws" or
+		// "registry.terraform.io/hashicorp/aws" - match on the type suffix
+		// rather than grabbing an arbitrary entry, since TF_REATTACH_PROVIDERS
+		// can legitimately list several reattached providers at once.
+		if strings.HasSuffix(addr, "/aws") {
+			cfg = c
+			found = true
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("no aws provider found in reattach config")
+	}
+
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig: tfplugin.Handshake,
+		Plugins:         tfplugin.VersionedPlugins[cfg.ProtocolVersion],
+		Reattach: &plugin.ReattachConfig{
+			Protocol: plugin.Protocol(cfg.Protocol),
+			Pid:      cfg.Pid,
+			Addr: &reattachAddr{
+				network: cfg.Addr.Network,
+				address: cfg.Addr.String,
+			},
+		},
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolNetRPC, plugin.ProtocolGRPC},
+	})
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, nil, fmt.Errorf("failed to connect to reattached provider: %s", err)
+	}
+
+	raw_, err := rpcClient.Dispense(tfplugin.ProviderPluginName)
+	if err != nil {
+		client.Kill()
+		return nil, nil, fmt.Errorf("failed to dispense reattached provider: %s", err)
+	}
+
+	p, ok := raw_.(terraform.ResourceProvider)
+	if !ok {
+		client.Kill()
+		return nil, nil, fmt.Errorf("reattached plugin does not implement terraform.ResourceProvider")
+	}
+
+	stop := func() {
+		if err := p.Stop(); err != nil {
+			log.WithError(err).Warn("failed to stop reattached provider")
+		}
+		client.Kill()
+	}
+
+	return p, stop, nil
+}
+
+// reattachAddr adapts the Network/String pair from TF_REATTACH_PROVIDERS
+// JSON to the net.Addr interface go-plugin's ReattachConfig expects.
+type reattachAddr struct {
+	network string
+	address string
+}
+
+func (a *reattachAddr) Network() string { return a.network }
+func (a *reattachAddr) String() string  { return a.address }
+
+// reattachConfigFromEnv returns the explicit --provider-reattach value when
+// set, falling back to TF_REATTACH_PROVIDERS so the flag is purely an
+// override rather than the only way to opt in.
+func reattachConfigFromEnv(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return os.Getenv("TF_REATTACH_PROVIDERS")
+}
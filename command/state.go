@@ -0,0 +1,136 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// terraformState is the subset of a Terraform state file we care about:
+// enough of the v3 (flat modules) and v4 (flat resources) layouts to
+// recover every resource's type and remote ID.
+type terraformState struct {
+	Version   int                      `json:"version"`
+	Resources []terraformStateResource `json:"resources"`
+	Modules   []terraformStateModuleV3 `json:"modules"`
+}
+
+// terraformStateResource is the v4 layout, where all resources (regardless
+// of which module they belong to) are listed in a single flat array.
+type terraformStateResource struct {
+	Type      string                   `json:"type"`
+	Instances []terraformStateInstance `json:"instances"`
+}
+
+// terraformStateModuleV3 is the v3 layout, where resources are nested
+// under each module under a "type.name" key instead of a top-level array.
+type terraformStateModuleV3 struct {
+	Resources map[string]terraformStateResourceV3 `json:"resources"`
+}
+
+type terraformStateResourceV3 struct {
+	Type    string                          `json:"type"`
+	Primary terraformStateInstancePrimaryV3 `json:"primary"`
+}
+
+// terraformStateInstancePrimaryV3 is the v3 "primary" object: the ID sits
+// directly under it, not nested under another "primary" key.
+type terraformStateInstancePrimaryV3 struct {
+	ID         string            `json:"id"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// terraformStateInstance is the v4 "instances[]" entry: the ID lives in
+// attributes["id"], there being no separate primary/instance split anymore.
+type terraformStateInstance struct {
+	Attributes map[string]string `json:"attributes"`
+	Provider   string            `json:"provider"`
+}
+
+// stateResourceKey identifies a resource the same way awsweeper's own
+// resource list does: by its Terraform type and its AWS ID.
+type stateResourceKey struct {
+	Type string
+	ID   string
+}
+
+// excludeStateFlag collects the repeatable --exclude-state flag values.
+type excludeStateFlag []string
+
+func (f *excludeStateFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *excludeStateFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// loadExcludedResources reads every state file or URL in paths and returns
+// the set of (resource type, id) tuples that must not be deleted.
+func loadExcludedResources(paths []string) (map[stateResourceKey]bool, error) {
+	excluded := map[stateResourceKey]bool{}
+
+	for _, path := range paths {
+		raw, err := readState(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read state %q: %s", path, err)
+		}
+
+		var state terraformState
+		if err := json.Unmarshal(raw, &state); err != nil {
+			return nil, fmt.Errorf("failed to parse state %q: %s", path, err)
+		}
+
+		before := len(excluded)
+		addStateResources(excluded, state)
+		log.Infof("excluded %d resource(s) tracked in state %q", len(excluded)-before, path)
+	}
+
+	return excluded, nil
+}
+
+// addStateResources merges the resources found in state into excluded,
+// supporting the legacy v3 per-module layout and the current v4 one.
+func addStateResources(excluded map[stateResourceKey]bool, state terraformState) {
+	for _, r := range state.Resources {
+		for _, inst := range r.Instances {
+			if id := inst.Attributes["id"]; id != "" {
+				excluded[stateResourceKey{Type: r.Type, ID: id}] = true
+			}
+		}
+	}
+
+	for _, m := range state.Modules {
+		for _, r := range m.Resources {
+			if r.Primary.ID != "" {
+				excluded[stateResourceKey{Type: r.Type, ID: r.Primary.ID}] = true
+			}
+		}
+	}
+}
+
+// readState loads the raw bytes of a state file from a local path or,
+// when path looks like a URL, from a remote backend (e.g. S3 presigned
+// or HTTP-served state).
+func readState(path string) ([]byte, error) {
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" {
+		resp, err := http.Get(path)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}
+
+	return ioutil.ReadFile(path)
+}
@@ -0,0 +1,95 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAddStateResourcesV3(t *testing.T) {
+	raw := []byte(`{
+		"version": 3,
+		"modules": [
+			{
+				"resources": {
+					"aws_instance.web": {
+						"type": "aws_instance",
+						"primary": {
+							"id": "i-0123456789abcdef0",
+							"attributes": {"id": "i-0123456789abcdef0"}
+						}
+					}
+				}
+			}
+		]
+	}`)
+
+	var state terraformState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		t.Fatalf("failed to unmarshal v3 fixture: %s", err)
+	}
+
+	excluded := map[stateResourceKey]bool{}
+	addStateResources(excluded, state)
+
+	want := stateResourceKey{Type: "aws_instance", ID: "i-0123456789abcdef0"}
+	if !excluded[want] {
+		t.Fatalf("expected %+v to be excluded, got %+v", want, excluded)
+	}
+	if len(excluded) != 1 {
+		t.Fatalf("expected exactly 1 excluded resource, got %d", len(excluded))
+	}
+}
+
+func TestAddStateResourcesV4(t *testing.T) {
+	raw := []byte(`{
+		"version": 4,
+		"resources": [
+			{
+				"type": "aws_instance",
+				"instances": [
+					{"attributes": {"id": "i-0fedcba9876543210"}}
+				]
+			}
+		]
+	}`)
+
+	var state terraformState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		t.Fatalf("failed to unmarshal v4 fixture: %s", err)
+	}
+
+	excluded := map[stateResourceKey]bool{}
+	addStateResources(excluded, state)
+
+	want := stateResourceKey{Type: "aws_instance", ID: "i-0fedcba9876543210"}
+	if !excluded[want] {
+		t.Fatalf("expected %+v to be excluded, got %+v", want, excluded)
+	}
+	if len(excluded) != 1 {
+		t.Fatalf("expected exactly 1 excluded resource, got %d", len(excluded))
+	}
+}
+
+func TestAddStateResourcesIgnoresMissingID(t *testing.T) {
+	raw := []byte(`{
+		"version": 4,
+		"resources": [
+			{"type": "aws_instance", "instances": [{"attributes": {}}]}
+		],
+		"modules": [
+			{"resources": {"aws_instance.web": {"type": "aws_instance", "primary": {}}}}
+		]
+	}`)
+
+	var state terraformState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		t.Fatalf("failed to unmarshal fixture: %s", err)
+	}
+
+	excluded := map[stateResourceKey]bool{}
+	addStateResources(excluded, state)
+
+	if len(excluded) != 0 {
+		t.Fatalf("expected no excluded resources, got %+v", excluded)
+	}
+}
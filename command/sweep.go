@@ -0,0 +1,144 @@
+package command
+
+import "strings"
+
+// allAwsRegions is used to expand --regions=all. It is not meant to track
+// every region AWS ever launches; new regions can be swept explicitly via
+// --regions until this list is updated.
+var allAwsRegions = []string{
+	"us-east-1", "us-east-2", "us-west-1", "us-west-2",
+	"eu-west-1", "eu-west-2", "eu-west-3", "eu-central-1", "eu-north-1",
+	"ap-northeast-1", "ap-northeast-2", "ap-southeast-1", "ap-southeast-2", "ap-south-1",
+	"sa-east-1", "ca-central-1",
+}
+
+// resourceDependents maps a resource type to the types that reference it and
+// therefore must be deleted first. It mirrors the ad-hoc ordering that
+// terraform-provider-aws's TestMain sweepers encode by hand (e.g. a launch
+// configuration can't be removed while an autoscaling group still uses it).
+var resourceDependents = map[string][]string{
+	"aws_launch_configuration": {"aws_autoscaling_group"},
+	"aws_security_group":       {"aws_instance"},
+	"aws_subnet":               {"aws_instance", "aws_autoscaling_group"},
+	"aws_vpc":                  {"aws_subnet", "aws_security_group", "aws_instance"},
+	"aws_iam_role":             {"aws_iam_instance_profile", "aws_autoscaling_group"},
+}
+
+// parseSweepRun splits the comma-separated --sweep-run value into resource
+// types, ignoring blank entries.
+func parseSweepRun(flag string) []string {
+	if flag == "" {
+		return nil
+	}
+
+	var types []string
+	for _, t := range strings.Split(flag, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			types = append(types, t)
+		}
+	}
+
+	return types
+}
+
+// expandWithDependents returns requested plus the transitive closure of
+// every type that depends on one of requested, so that e.g. asking for
+// aws_launch_configuration also pulls in aws_autoscaling_group.
+func expandWithDependents(requested []string) []string {
+	seen := map[string]bool{}
+	var queue []string
+
+	for _, t := range requested {
+		if !seen[t] {
+			seen[t] = true
+			queue = append(queue, t)
+		}
+	}
+
+	for i := 0; i < len(queue); i++ {
+		for _, dependent := range resourceDependents[queue[i]] {
+			if !seen[dependent] {
+				seen[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	return queue
+}
+
+// sortByDeletionOrder topologically sorts types so that every type appears
+// before the types it depends on (i.e. a dependent is deleted before the
+// resource it references), using Kahn's algorithm. Types with no recorded
+// relationship keep their relative input order.
+func sortByDeletionOrder(types []string) []string {
+	included := map[string]bool{}
+	for _, t := range types {
+		included[t] = true
+	}
+
+	// edge dependency -> dependent: the dependent must be deleted first,
+	// so it must come before the dependency in the result.
+	indegree := map[string]int{}
+	edges := map[string][]string{}
+	for _, t := range types {
+		indegree[t] = 0
+	}
+	for dependency, dependents := range resourceDependents {
+		if !included[dependency] {
+			continue
+		}
+		for _, dependent := range dependents {
+			if !included[dependent] {
+				continue
+			}
+			edges[dependent] = append(edges[dependent], dependency)
+			indegree[dependency]++
+		}
+	}
+
+	var queue, ordered []string
+	for _, t := range types {
+		if indegree[t] == 0 {
+			queue = append(queue, t)
+		}
+	}
+
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		ordered = append(ordered, t)
+
+		for _, next := range edges[t] {
+			indegree[next]--
+			if indegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return ordered
+}
+
+// resolveRegions expands a comma-separated --regions value into a region
+// list, treating the literal "all" as every region in allRegions.
+func resolveRegions(flag string, fallback string, allRegions []string) []string {
+	if flag == "" {
+		return []string{fallback}
+	}
+
+	if flag == "all" {
+		return allRegions
+	}
+
+	var regions []string
+	for _, r := range strings.Split(flag, ",") {
+		r = strings.TrimSpace(r)
+		if r != "" {
+			regions = append(regions, r)
+		}
+	}
+
+	return regions
+}
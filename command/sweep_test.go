@@ -0,0 +1,56 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandWithDependents(t *testing.T) {
+	got := expandWithDependents([]string{"aws_launch_configuration"})
+	want := []string{"aws_launch_configuration", "aws_autoscaling_group"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expandWithDependents() = %v, want %v", got, want)
+	}
+}
+
+func TestExpandWithDependentsNoDependents(t *testing.T) {
+	got := expandWithDependents([]string{"aws_s3_bucket"})
+	want := []string{"aws_s3_bucket"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expandWithDependents() = %v, want %v", got, want)
+	}
+}
+
+func TestSortByDeletionOrderPutsDependentsFirst(t *testing.T) {
+	types := sortByDeletionOrder(expandWithDependents([]string{"aws_launch_configuration"}))
+
+	indexOf := func(t string) int {
+		for i, v := range types {
+			if v == t {
+				return i
+			}
+		}
+		return -1
+	}
+
+	asg := indexOf("aws_autoscaling_group")
+	lc := indexOf("aws_launch_configuration")
+
+	if asg == -1 || lc == -1 {
+		t.Fatalf("expected both types present, got %v", types)
+	}
+	if asg > lc {
+		t.Fatalf("expected aws_autoscaling_group before aws_launch_configuration, got %v", types)
+	}
+}
+
+func TestSortByDeletionOrderUnrelatedTypesKeepOrder(t *testing.T) {
+	got := sortByDeletionOrder([]string{"aws_s3_bucket", "aws_dynamodb_table"})
+	want := []string{"aws_s3_bucket", "aws_dynamodb_table"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sortByDeletionOrder() = %v, want %v", got, want)
+	}
+}
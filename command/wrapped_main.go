@@ -1,11 +1,14 @@
 package command
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	goLog "log"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -17,8 +20,18 @@ import (
 	terraformProviderAWS "github.com/terraform-providers/terraform-provider-aws/aws"
 )
 
-// WrappedMain is the actual main function that does not exit for acceptance testing purposes
+// WrappedMain is the actual main function that does not exit for acceptance testing purposes.
+// It re-execs itself under panicwrap so that a panic anywhere below is caught, written to a
+// crash log, and reported to the user instead of just dumping a stack trace to stderr.
 func WrappedMain() int {
+	if exitStatus, handled := wrapPanic(); handled {
+		return exitStatus
+	}
+
+	return realMain()
+}
+
+func realMain() int {
 	app := "awsweeper"
 	version := "v0.4.1"
 
@@ -31,6 +44,12 @@ func WrappedMain() int {
 	region := set.String("region", "", "The region to use. Overrides config/env settings")
 	maxRetries := set.Int("max-retries", 25, "The maximum number of times an AWS API request is being executed")
 	outputType := set.String("output", "string", "The type of output result (String, JSON or YAML) default: String")
+	var excludeState excludeStateFlag
+	set.Var(&excludeState, "exclude-state", "Path or URL of a Terraform state file whose resources should never be deleted (repeatable)")
+	shutdownGrace := set.Duration("shutdown-grace", defaultShutdownGrace, "How long in-flight deletes get to finish after the first Ctrl-C before they are force-killed; a second Ctrl-C force-kills them at once")
+	regionsFlag := set.String("regions", "", "Comma-separated list of regions to sweep in parallel, or \"all\". Overrides --region")
+	sweepRunFlag := set.String("sweep-run", "", "Comma-separated allowlist of resource types to delete; their dependents are pulled in automatically")
+	providerReattach := set.String("provider-reattach", "", "Reattach to an already-running terraform-provider-aws instead of starting one in-process (same JSON format as TF_REATTACH_PROVIDERS, which is honored automatically if this is unset)")
 
 	// discard internal logs of Terraform AWS provider
 	goLog.SetOutput(ioutil.Discard)
@@ -39,8 +58,13 @@ func WrappedMain() int {
 		fmt.Println(help())
 	}
 
-	err := set.Parse(os.Args[1:])
+	args, err := prependEnvArgs(os.Args[1:])
 	if err != nil {
+		log.WithError(err).Error("failed to parse AWSWEEPER_CLI_ARGS")
+		return 1
+	}
+
+	if err := set.Parse(args); err != nil {
 		// the Parse function prints already an error + help message, so we don't want to output it here again
 		log.WithError(err).Debug("failed to parse command line arguments")
 		return 1
@@ -56,21 +80,114 @@ func WrappedMain() int {
 		return 0
 	}
 
+	excludedResources, err := loadExcludedResources(excludeState)
+	if err != nil {
+		log.WithError(err).Fatal("failed to load --exclude-state")
+	}
+
+	reattach := reattachConfigFromEnv(*providerReattach)
+	regions := resolveRegions(*regionsFlag, *region, allAwsRegions)
+
+	if len(regions) > 1 {
+		if reattach != "" {
+			log.Fatal("--provider-reattach reattaches to a single already-configured provider process and cannot be combined with --regions for more than one region")
+		}
+		if !*forceDeleteFlag {
+			log.Fatal("--regions with more than one region requires --force, since per-region confirmation prompts would race over the same stdin")
+		}
+	}
+
+	ctx, killCtx, cancel := newShutdownContext(*shutdownGrace)
+	defer cancel()
+
+	types := sortByDeletionOrder(expandWithDependents(parseSweepRun(*sweepRunFlag)))
+
+	cfg := regionRunConfig{
+		app:               app,
+		version:           version,
+		args:              set.Args(),
+		profile:           *profile,
+		maxRetries:        *maxRetries,
+		dryRun:            *dryRunFlag,
+		forceDelete:       *forceDeleteFlag,
+		outputType:        *outputType,
+		excludedResources: excludedResources,
+		types:             types,
+		ctx:               ctx,
+		killCtx:           killCtx,
+		reattach:          reattach,
+	}
+
+	return runRegions(cfg, regions)
+}
+
+// regionRunConfig bundles everything a single region's run needs, so that
+// sweeping many regions in parallel doesn't require threading a long
+// parameter list through goroutines.
+type regionRunConfig struct {
+	app, version      string
+	args              []string
+	profile           string
+	maxRetries        int
+	dryRun            bool
+	forceDelete       bool
+	outputType        string
+	excludedResources map[stateResourceKey]bool
+	types             []string
+	ctx, killCtx      context.Context
+	reattach          string
+}
+
+// runRegions runs cfg against every region, concurrently when there is more
+// than one, and returns the worst (highest, non-zero preferred) exit status
+// across all of them.
+func runRegions(cfg regionRunConfig, regions []string) int {
+	if len(regions) == 1 {
+		return runRegion(cfg, regions[0])
+	}
+
+	log.Infof("sweeping %d regions in parallel: %s", len(regions), strings.Join(regions, ", "))
+
+	statuses := make([]int, len(regions))
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func(i int, region string) {
+			defer wg.Done()
+			statuses[i] = runRegion(cfg, region)
+		}(i, region)
+	}
+	wg.Wait()
+
+	exitStatus := 0
+	for _, s := range statuses {
+		if s > exitStatus {
+			exitStatus = s
+		}
+	}
+
+	return exitStatus
+}
+
+// runRegion runs a single wipe invocation against one region using the
+// shared YAML config and flags carried in cfg.
+func runRegion(cfg regionRunConfig, region string) int {
 	c := &cli.CLI{
-		Name:     app,
-		Version:  version,
-		HelpFunc: basicHelpFunc(app),
+		Name:     cfg.app,
+		Version:  cfg.version,
+		HelpFunc: basicHelpFunc(cfg.app),
 	}
-	c.Args = append([]string{"wipe"}, set.Args()...)
+	c.Args = append([]string{"wipe"}, cfg.args...)
 
 	sess := session.Must(session.NewSessionWithOptions(session.Options{
-		Config:            aws.Config{Region: region},
+		Config:            aws.Config{Region: aws.String(region)},
 		SharedConfigState: session.SharedConfigEnable,
-		Profile:           *profile,
+		Profile:           cfg.profile,
 	}))
 	log.Infof("using region: %s", *sess.Config.Region)
 
-	p := initAwsProvider(*profile, *sess.Config.Region, *maxRetries)
+	p, stopProvider := initAwsProvider(cfg.profile, *sess.Config.Region, cfg.maxRetries, cfg.reattach)
+	defer stopProvider()
 
 	ui := &cli.BasicUi{
 		Reader:      os.Stdin,
@@ -78,7 +195,7 @@ func WrappedMain() int {
 		ErrorWriter: os.Stderr,
 	}
 
-	client := resource.NewAWS(sess)
+	client := resource.NewAWS(sess, cfg.excludedResources, cfg.ctx, cfg.killCtx)
 
 	c.Commands = map[string]cli.CommandFactory{
 		"wipe": func() (cli.Command, error) {
@@ -89,9 +206,10 @@ func WrappedMain() int {
 				},
 				client:      client,
 				provider:    p,
-				dryRun:      *dryRunFlag,
-				forceDelete: *forceDeleteFlag,
-				outputType:  *outputType,
+				dryRun:      cfg.dryRun,
+				forceDelete: cfg.forceDelete,
+				outputType:  cfg.outputType,
+				only:        cfg.types,
 			}, nil
 		},
 	}
@@ -120,8 +238,20 @@ Options:
   --force				Start deleting without asking for confirmation
 
   --max-retries				The maximum number of times an AWS API request is being executed
-  
+
   --output				The type of output result (string, json or yaml) default: string
+
+  --exclude-state			Path or URL of a Terraform state file whose resources should never be deleted (repeatable)
+
+  --shutdown-grace			How long in-flight deletes get to finish after the first Ctrl-C before they are force-killed; a second Ctrl-C force-kills them at once (default 10s)
+
+  --regions				Comma-separated list of regions to sweep in parallel, or "all". Overrides --region. Requires --force when more than one region is given
+
+  --sweep-run				Comma-separated allowlist of resource types to delete; their dependents are pulled in automatically
+
+  --provider-reattach			Reattach to an already-running terraform-provider-aws instead of starting one in-process (honors TF_REATTACH_PROVIDERS if unset)
+
+AWSWEEPER_CLI_ARGS and AWSWEEPER_CLI_ARGS_wipe are tokenized and prepended to the command line, the same way Terraform honors TF_CLI_ARGS.
 `
 }
 
@@ -131,7 +261,20 @@ func basicHelpFunc(app string) cli.HelpFunc {
 	}
 }
 
-func initAwsProvider(profile string, region string, maxRetries int) *terraform.ResourceProvider {
+// initAwsProvider returns a configured terraform-provider-aws, either
+// in-process or, when reattach is non-empty, by dialing an already-running
+// provider over gRPC via go-plugin. The returned func must be called on
+// shutdown; for the in-process provider it is a no-op, for the reattached
+// one it stops the provider and kills the plugin client.
+func initAwsProvider(profile string, region string, maxRetries int, reattach string) (*terraform.ResourceProvider, func()) {
+	if reattach != "" {
+		p, stop, err := reattachProvider(reattach)
+		if err != nil {
+			log.WithError(err).Fatal("failed to reattach to terraform-provider-aws")
+		}
+		return &p, stop
+	}
+
 	p := terraformProviderAWS.Provider()
 
 	cfg := map[string]interface{}{
@@ -158,5 +301,5 @@ func initAwsProvider(profile string, region string, maxRetries int) *terraform.R
 		log.WithError(err).Fatalf("failed to configure Terraform AWS provider")
 	}
 
-	return &p
+	return &p, func() {}
 }